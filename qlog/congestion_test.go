@@ -0,0 +1,56 @@
+package qlog_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+var _ = Describe("Congestion-controller and loss-timer events", func() {
+	var buf *closableBuffer
+
+	BeforeEach(func() {
+		buf = &closableBuffer{}
+	})
+
+	It("emits recovery:congestion_state_updated with the new state", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.UpdatedCongestionState(time.Now(), qlog.CongestionStateRecovery)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("recovery:congestion_state_updated"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["new"]).To(Equal("recovery"))
+	})
+
+	It("emits recovery:loss_timer_updated with event_type set on LossTimerSet", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.LossTimerSet(time.Now(), qlog.TimerTypePTO, protocol.EncryptionLevelInitial, time.Now().Add(100*time.Millisecond))
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("recovery:loss_timer_updated"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["event_type"]).To(Equal("set"))
+		Expect(data["timer_type"]).To(Equal("pto"))
+		Expect(data).To(HaveKey("delta"))
+	})
+
+	It("emits recovery:loss_timer_updated with event_type expired and no delta on LossTimerExpired", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.LossTimerExpired(time.Now(), qlog.TimerTypeACK, protocol.EncryptionLevelHandshake)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("recovery:loss_timer_updated"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["event_type"]).To(Equal("expired"))
+		Expect(data["timer_type"]).To(Equal("ack"))
+		Expect(data).ToNot(HaveKey("delta"))
+	})
+})
@@ -0,0 +1,101 @@
+package qlog_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+var _ = Describe("Connection-level error tracing", func() {
+	var buf *closableBuffer
+
+	BeforeEach(func() {
+		buf = &closableBuffer{}
+	})
+
+	It("emits connectivity:connection_closed with only a trigger for idle_timeout", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ClosedConnection(time.Now(), qlog.NewIdleTimeoutCloseReason())
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("connectivity:connection_closed"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["trigger"]).To(Equal("idle_timeout"))
+		Expect(data).ToNot(HaveKey("error_code"))
+		Expect(data).ToNot(HaveKey("reason"))
+		Expect(data).ToNot(HaveKey("frame_type"))
+	})
+
+	It("includes error_code and reason, but not frame_type, for an application close", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ClosedConnection(time.Now(), qlog.NewApplicationCloseReason(1337, "bye"))
+		Expect(tracer.Export()).To(Succeed())
+
+		data := singleEvent(buf)["data"].(map[string]interface{})
+		Expect(data["trigger"]).To(Equal("application_close"))
+		Expect(data["error_code"]).To(Equal(float64(1337)))
+		Expect(data["reason"]).To(Equal("bye"))
+		Expect(data).ToNot(HaveKey("frame_type"))
+	})
+
+	It("includes error_code, reason and frame_type for a transport close", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ClosedConnection(time.Now(), qlog.NewTransportCloseReason(10, 0x1c, "protocol violation"))
+		Expect(tracer.Export()).To(Succeed())
+
+		data := singleEvent(buf)["data"].(map[string]interface{})
+		Expect(data["trigger"]).To(Equal("transport_close"))
+		Expect(data["error_code"]).To(Equal(float64(10)))
+		Expect(data["frame_type"]).To(Equal(float64(0x1c)))
+		Expect(data["reason"]).To(Equal("protocol violation"))
+	})
+
+	It("emits only a trigger for a stateless reset or version mismatch close", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ClosedConnection(time.Now(), qlog.NewStatelessResetCloseReason())
+		Expect(tracer.Export()).To(Succeed())
+
+		data := singleEvent(buf)["data"].(map[string]interface{})
+		Expect(data["trigger"]).To(Equal("stateless_reset"))
+		Expect(data).ToNot(HaveKey("error_code"))
+
+		buf = &closableBuffer{}
+		tracer = qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ClosedConnection(time.Now(), qlog.NewVersionMismatchCloseReason())
+		Expect(tracer.Export()).To(Succeed())
+
+		data = singleEvent(buf)["data"].(map[string]interface{})
+		Expect(data["trigger"]).To(Equal("version_mismatch"))
+		Expect(data).ToNot(HaveKey("error_code"))
+	})
+
+	It("emits connectivity:stateless_reset_received with the token", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		token := protocol.StatelessResetToken{0xde, 0xad, 0xbe, 0xef}
+		tracer.ReceivedStatelessReset(time.Now(), &token)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("connectivity:stateless_reset_received"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["stateless_reset_token"]).To(Equal("deadbeef000000000000000000000000"))
+	})
+
+	It("emits connectivity:version_information with the offered versions", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ReceivedVersionNegotiationPacket(time.Now(), []protocol.VersionNumber{1, 0xff00001d})
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("connectivity:version_information"))
+		data := ev["data"].(map[string]interface{})
+		versions, ok := data["versions"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(versions).To(HaveLen(2))
+	})
+})
@@ -0,0 +1,96 @@
+package qlog_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// singleEvent splits what an already exported streaming tracer wrote into buf,
+// expects exactly one non-header event record, and returns it decoded into a
+// generic map.
+func singleEvent(buf *closableBuffer) map[string]interface{} {
+	records, truncated := splitJSONSeqRecords(buf.Bytes())
+	ExpectWithOffset(1, truncated).To(BeFalse())
+	ExpectWithOffset(1, records).To(HaveLen(2)) // header + the one event
+
+	var ev map[string]interface{}
+	ExpectWithOffset(1, json.Unmarshal(records[1], &ev)).To(Succeed())
+	return ev
+}
+
+var _ = Describe("DATAGRAM frame events", func() {
+	var buf *closableBuffer
+
+	BeforeEach(func() {
+		buf = &closableBuffer{}
+	})
+
+	It("emits transport:datagram_frame_sent with frame_type and length", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.SentDatagramFrame(time.Now(), 42)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("transport:datagram_frame_sent"))
+		data, ok := ev["data"].(map[string]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(data["frame_type"]).To(Equal("datagram"))
+		Expect(data["length"]).To(Equal(float64(42)))
+	})
+
+	It("emits transport:datagram_frame_received with frame_type and length", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.ReceivedDatagramFrame(time.Now(), 17)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("transport:datagram_frame_received"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["frame_type"]).To(Equal("datagram"))
+		Expect(data["length"]).To(Equal(float64(17)))
+	})
+
+	It("emits transport:datagram_frame_dropped with the drop trigger", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.DroppedDatagramFrame(time.Now(), 9, qlog.DatagramDropReasonTooLarge)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		Expect(ev["name"]).To(Equal("transport:datagram_frame_dropped"))
+		data := ev["data"].(map[string]interface{})
+		Expect(data["length"]).To(Equal(float64(9)))
+		Expect(data["trigger"]).To(Equal("too_large"))
+	})
+
+	// This specs the *wire.DatagramFrame case that still needs adding to
+	// qlog/frame.go's transformFrame switch (see the Tracer interface doc on
+	// SentPacket); that file isn't part of this tree, so this only holds once
+	// that case lands there.
+	It("serializes a wire.DatagramFrame inside SentPacket as frame_type datagram", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.SentPacket(
+			time.Now(),
+			&wire.ExtendedHeader{},
+			100,
+			nil,
+			[]wire.Frame{&wire.DatagramFrame{Data: []byte("hello")}},
+		)
+		Expect(tracer.Export()).To(Succeed())
+
+		ev := singleEvent(buf)
+		data := ev["data"].(map[string]interface{})
+		frames, ok := data["frames"].([]interface{})
+		Expect(ok).To(BeTrue())
+		Expect(frames).To(HaveLen(1))
+		f := frames[0].(map[string]interface{})
+		Expect(f["frame_type"]).To(Equal("datagram"))
+		Expect(f["length"]).To(Equal(float64(len("hello"))))
+	})
+})
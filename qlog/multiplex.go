@@ -0,0 +1,361 @@
+package qlog
+
+import (
+	"io"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+)
+
+// multiTracer fans out every call to a set of Tracers, e.g. to record a qlog
+// to disk while simultaneously feeding live metrics to Prometheus.
+type multiTracer []Tracer
+
+var _ Tracer = multiTracer(nil)
+
+// NewMultiTracer creates a Tracer that forwards every call to all of tracers.
+func NewMultiTracer(tracers ...Tracer) Tracer {
+	return multiTracer(tracers)
+}
+
+func (m multiTracer) Export() error {
+	var firstErr error
+	for _, t := range m {
+		if err := t.Export(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func (m multiTracer) StartedConnection(t time.Time, local, remote net.Addr, version protocol.VersionNumber, srcConnID, destConnID protocol.ConnectionID) {
+	for _, tr := range m {
+		tr.StartedConnection(t, local, remote, version, srcConnID, destConnID)
+	}
+}
+
+func (m multiTracer) SentPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame) {
+	for _, tr := range m {
+		tr.SentPacket(t, hdr, packetSize, ack, frames)
+	}
+}
+
+func (m multiTracer) ReceivedRetry(t time.Time, hdr *wire.Header) {
+	for _, tr := range m {
+		tr.ReceivedRetry(t, hdr)
+	}
+}
+
+func (m multiTracer) ReceivedPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame) {
+	for _, tr := range m {
+		tr.ReceivedPacket(t, hdr, packetSize, frames)
+	}
+}
+
+func (m multiTracer) BufferedPacket(t time.Time, packetType PacketType) {
+	for _, tr := range m {
+		tr.BufferedPacket(t, packetType)
+	}
+}
+
+func (m multiTracer) SentDatagramFrame(t time.Time, length protocol.ByteCount) {
+	for _, tr := range m {
+		tr.SentDatagramFrame(t, length)
+	}
+}
+
+func (m multiTracer) ReceivedDatagramFrame(t time.Time, length protocol.ByteCount) {
+	for _, tr := range m {
+		tr.ReceivedDatagramFrame(t, length)
+	}
+}
+
+func (m multiTracer) DroppedDatagramFrame(t time.Time, length protocol.ByteCount, reason DatagramDropReason) {
+	for _, tr := range m {
+		tr.DroppedDatagramFrame(t, length, reason)
+	}
+}
+
+func (m multiTracer) UpdatedMetrics(t time.Time, rttStats *congestion.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	for _, tr := range m {
+		tr.UpdatedMetrics(t, rttStats, cwnd, bytesInFlight, packetsInFlight)
+	}
+}
+
+func (m multiTracer) LostPacket(t time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, reason PacketLossReason, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	for _, tr := range m {
+		tr.LostPacket(t, encLevel, pn, reason, bytesInFlight, packetsInFlight)
+	}
+}
+
+func (m multiTracer) UpdatedPTOCount(t time.Time, value uint32) {
+	for _, tr := range m {
+		tr.UpdatedPTOCount(t, value)
+	}
+}
+
+func (m multiTracer) UpdatedKeyFromTLS(t time.Time, encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
+	for _, tr := range m {
+		tr.UpdatedKeyFromTLS(t, encLevel, pers)
+	}
+}
+
+func (m multiTracer) UpdatedKey(t time.Time, generation protocol.KeyPhase, remote bool) {
+	for _, tr := range m {
+		tr.UpdatedKey(t, generation, remote)
+	}
+}
+
+func (m multiTracer) ReceivedStatelessReset(t time.Time, token *protocol.StatelessResetToken) {
+	for _, tr := range m {
+		tr.ReceivedStatelessReset(t, token)
+	}
+}
+
+func (m multiTracer) ReceivedVersionNegotiationPacket(t time.Time, versions []protocol.VersionNumber) {
+	for _, tr := range m {
+		tr.ReceivedVersionNegotiationPacket(t, versions)
+	}
+}
+
+func (m multiTracer) ClosedConnection(t time.Time, reason CloseReason) {
+	for _, tr := range m {
+		tr.ClosedConnection(t, reason)
+	}
+}
+
+func (m multiTracer) UpdatedCongestionState(t time.Time, state CongestionState) {
+	for _, tr := range m {
+		tr.UpdatedCongestionState(t, state)
+	}
+}
+
+func (m multiTracer) LossTimerSet(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel, deadline time.Time) {
+	for _, tr := range m {
+		tr.LossTimerSet(t, timerType, encLevel, deadline)
+	}
+}
+
+func (m multiTracer) LossTimerExpired(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel) {
+	for _, tr := range m {
+		tr.LossTimerExpired(t, timerType, encLevel)
+	}
+}
+
+// FilterOptions configures the behavior of a filteredTracer (see NewFilteredTracer).
+type FilterOptions struct {
+	// DropPacketEvents, if set, suppresses SentPacket and ReceivedPacket events
+	// while still forwarding events like UpdatedMetrics and LostPacket.
+	DropPacketEvents bool
+	// MetricsSampleRate, if greater than 1, only forwards 1 in N UpdatedMetrics
+	// events to inner. A value of 0 or 1 forwards every event.
+	MetricsSampleRate int
+	// RotateBytes, if greater than 0, closes the current writer and opens a new
+	// one (via NewWriter) once approximately this many bytes of qlog output have
+	// been produced. Output size is estimated from the wire size of packets
+	// actually forwarded to inner; it is not an exact byte count of the qlog
+	// file, and packets suppressed by DropPacketEvents don't count towards it.
+	RotateBytes int64
+	// NewWriter creates the writer for the next qlog file. It must be set if
+	// RotateBytes is greater than 0.
+	NewWriter func() (io.WriteCloser, error)
+	// NewTracer builds the Tracer that wraps the writer returned by NewWriter.
+	// It must produce the same kind of Tracer (e.g. NewTracer vs.
+	// NewStreamingTracer) that the original inner Tracer was created with, or
+	// rotated files will silently switch qlog format partway through a
+	// connection. It must be set if RotateBytes is greater than 0.
+	NewTracer func(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) Tracer
+	// Perspective and ODCID are used to start a new Tracer on rotation. They
+	// must match the values the original inner Tracer was created with.
+	Perspective protocol.Perspective
+	ODCID       protocol.ConnectionID
+}
+
+// filteredTracer wraps a Tracer, dropping or sampling events according to
+// FilterOptions. Filtering happens synchronously in the calling goroutine, so
+// the eventChanSize=50 blocking-send semantics of the wrapped tracer are
+// unaffected: a call either is dropped before reaching inner, or is forwarded
+// exactly as every other Tracer method call would be. Unlike the base
+// tracer, which serializes access to its state via a single consumer
+// goroutine, filteredTracer may be called concurrently (e.g. from both the
+// send and receive paths), so mu guards inner, metricsSeen and bytesWritten.
+type filteredTracer struct {
+	mu    sync.Mutex
+	inner Tracer
+	opts  FilterOptions
+
+	metricsSeen  int
+	bytesWritten int64
+}
+
+var _ Tracer = &filteredTracer{}
+
+// NewFilteredTracer wraps inner, applying opts to every call before forwarding it.
+func NewFilteredTracer(inner Tracer, opts FilterOptions) Tracer {
+	return &filteredTracer{inner: inner, opts: opts}
+}
+
+// rotateIfNeeded accounts bytes that were actually forwarded to inner, and
+// rotates to a fresh writer + tracer once the configured threshold is crossed.
+// Call it only after confirming the event was (or is about to be) forwarded,
+// never for events DropPacketEvents suppressed. f.mu must be held by the
+// caller. The new writer and tracer are built before f.inner is swapped, and
+// the old inner is only Exported once the swap has succeeded, so a
+// NewWriter error leaves f.inner on the still-usable previous tracer instead
+// of one that has already been closed.
+func (f *filteredTracer) rotateIfNeeded(forwardedBytes protocol.ByteCount) {
+	if f.opts.RotateBytes <= 0 || f.opts.NewWriter == nil || f.opts.NewTracer == nil {
+		return
+	}
+	f.bytesWritten += int64(forwardedBytes)
+	if f.bytesWritten < f.opts.RotateBytes {
+		return
+	}
+	w, err := f.opts.NewWriter()
+	if err != nil {
+		return
+	}
+	next := f.opts.NewTracer(w, f.opts.Perspective, f.opts.ODCID)
+	prev := f.inner
+	f.inner = next
+	f.bytesWritten = 0
+	prev.Export()
+}
+
+func (f *filteredTracer) Export() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.inner.Export()
+}
+
+func (f *filteredTracer) StartedConnection(t time.Time, local, remote net.Addr, version protocol.VersionNumber, srcConnID, destConnID protocol.ConnectionID) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.StartedConnection(t, local, remote, version, srcConnID, destConnID)
+}
+
+func (f *filteredTracer) SentPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opts.DropPacketEvents {
+		return
+	}
+	f.inner.SentPacket(t, hdr, packetSize, ack, frames)
+	f.rotateIfNeeded(packetSize)
+}
+
+func (f *filteredTracer) ReceivedRetry(t time.Time, hdr *wire.Header) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.ReceivedRetry(t, hdr)
+}
+
+func (f *filteredTracer) ReceivedPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opts.DropPacketEvents {
+		return
+	}
+	f.inner.ReceivedPacket(t, hdr, packetSize, frames)
+	f.rotateIfNeeded(packetSize)
+}
+
+func (f *filteredTracer) BufferedPacket(t time.Time, packetType PacketType) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.BufferedPacket(t, packetType)
+}
+
+func (f *filteredTracer) SentDatagramFrame(t time.Time, length protocol.ByteCount) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.SentDatagramFrame(t, length)
+}
+
+func (f *filteredTracer) ReceivedDatagramFrame(t time.Time, length protocol.ByteCount) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.ReceivedDatagramFrame(t, length)
+}
+
+func (f *filteredTracer) DroppedDatagramFrame(t time.Time, length protocol.ByteCount, reason DatagramDropReason) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.DroppedDatagramFrame(t, length, reason)
+}
+
+func (f *filteredTracer) UpdatedMetrics(t time.Time, rttStats *congestion.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.opts.MetricsSampleRate > 1 {
+		f.metricsSeen++
+		if (f.metricsSeen-1)%f.opts.MetricsSampleRate != 0 {
+			return
+		}
+	}
+	f.inner.UpdatedMetrics(t, rttStats, cwnd, bytesInFlight, packetsInFlight)
+}
+
+func (f *filteredTracer) LostPacket(t time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, reason PacketLossReason, bytesInFlight protocol.ByteCount, packetsInFlight int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.LostPacket(t, encLevel, pn, reason, bytesInFlight, packetsInFlight)
+}
+
+func (f *filteredTracer) UpdatedPTOCount(t time.Time, value uint32) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.UpdatedPTOCount(t, value)
+}
+
+func (f *filteredTracer) UpdatedKeyFromTLS(t time.Time, encLevel protocol.EncryptionLevel, pers protocol.Perspective) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.UpdatedKeyFromTLS(t, encLevel, pers)
+}
+
+func (f *filteredTracer) UpdatedKey(t time.Time, generation protocol.KeyPhase, remote bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.UpdatedKey(t, generation, remote)
+}
+
+func (f *filteredTracer) ReceivedStatelessReset(t time.Time, token *protocol.StatelessResetToken) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.ReceivedStatelessReset(t, token)
+}
+
+func (f *filteredTracer) ReceivedVersionNegotiationPacket(t time.Time, versions []protocol.VersionNumber) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.ReceivedVersionNegotiationPacket(t, versions)
+}
+
+func (f *filteredTracer) ClosedConnection(t time.Time, reason CloseReason) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.ClosedConnection(t, reason)
+}
+
+func (f *filteredTracer) UpdatedCongestionState(t time.Time, state CongestionState) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.UpdatedCongestionState(t, state)
+}
+
+func (f *filteredTracer) LossTimerSet(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel, deadline time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.LossTimerSet(t, timerType, encLevel, deadline)
+}
+
+func (f *filteredTracer) LossTimerExpired(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.inner.LossTimerExpired(t, timerType, encLevel)
+}
@@ -0,0 +1,162 @@
+package qlog_test
+
+import (
+	"errors"
+	"io"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lucas-clemente/quic-go/internal/congestion"
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/internal/wire"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// recordingTracer is a minimal qlog.Tracer that counts calls instead of
+// serializing anything, so tests can assert on what NewMultiTracer and
+// NewFilteredTracer forward without going through the gojay encoder.
+type recordingTracer struct {
+	exportErr error
+
+	exported        int
+	sentPackets     []protocol.ByteCount
+	receivedPackets []protocol.ByteCount
+	updatedMetrics  int
+}
+
+func (r *recordingTracer) Export() error { r.exported++; return r.exportErr }
+func (r *recordingTracer) StartedConnection(time.Time, net.Addr, net.Addr, protocol.VersionNumber, protocol.ConnectionID, protocol.ConnectionID) {
+}
+func (r *recordingTracer) SentPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame) {
+	r.sentPackets = append(r.sentPackets, packetSize)
+}
+func (r *recordingTracer) ReceivedRetry(time.Time, *wire.Header) {}
+func (r *recordingTracer) ReceivedPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame) {
+	r.receivedPackets = append(r.receivedPackets, packetSize)
+}
+func (r *recordingTracer) BufferedPacket(time.Time, qlog.PacketType)           {}
+func (r *recordingTracer) SentDatagramFrame(time.Time, protocol.ByteCount)     {}
+func (r *recordingTracer) ReceivedDatagramFrame(time.Time, protocol.ByteCount) {}
+func (r *recordingTracer) DroppedDatagramFrame(time.Time, protocol.ByteCount, qlog.DatagramDropReason) {
+}
+func (r *recordingTracer) UpdatedMetrics(time.Time, *congestion.RTTStats, protocol.ByteCount, protocol.ByteCount, int) {
+	r.updatedMetrics++
+}
+func (r *recordingTracer) LostPacket(time.Time, protocol.EncryptionLevel, protocol.PacketNumber, qlog.PacketLossReason, protocol.ByteCount, int) {
+}
+func (r *recordingTracer) UpdatedPTOCount(time.Time, uint32) {}
+func (r *recordingTracer) UpdatedKeyFromTLS(time.Time, protocol.EncryptionLevel, protocol.Perspective) {
+}
+func (r *recordingTracer) UpdatedKey(time.Time, protocol.KeyPhase, bool)                        {}
+func (r *recordingTracer) ReceivedStatelessReset(time.Time, *protocol.StatelessResetToken)      {}
+func (r *recordingTracer) ReceivedVersionNegotiationPacket(time.Time, []protocol.VersionNumber) {}
+func (r *recordingTracer) ClosedConnection(time.Time, qlog.CloseReason)                         {}
+func (r *recordingTracer) UpdatedCongestionState(time.Time, qlog.CongestionState)               {}
+func (r *recordingTracer) LossTimerSet(time.Time, qlog.TimerType, protocol.EncryptionLevel, time.Time) {
+}
+func (r *recordingTracer) LossTimerExpired(time.Time, qlog.TimerType, protocol.EncryptionLevel) {}
+
+var _ qlog.Tracer = &recordingTracer{}
+
+func sentPacketOf(size protocol.ByteCount) (time.Time, *wire.ExtendedHeader, protocol.ByteCount, *wire.AckFrame, []wire.Frame) {
+	return time.Now(), &wire.ExtendedHeader{}, size, nil, nil
+}
+
+var _ = Describe("MultiTracer", func() {
+	It("fans out every call to all tracers", func() {
+		a, b := &recordingTracer{}, &recordingTracer{}
+		multi := qlog.NewMultiTracer(a, b)
+
+		multi.SentPacket(sentPacketOf(123))
+		Expect(a.sentPackets).To(Equal([]protocol.ByteCount{123}))
+		Expect(b.sentPackets).To(Equal([]protocol.ByteCount{123}))
+	})
+
+	It("exports every tracer and returns the first error", func() {
+		first := errors.New("first error")
+		a := &recordingTracer{exportErr: first}
+		b := &recordingTracer{exportErr: errors.New("second error")}
+		multi := qlog.NewMultiTracer(a, b)
+
+		Expect(multi.Export()).To(MatchError(first))
+		Expect(a.exported).To(Equal(1))
+		Expect(b.exported).To(Equal(1))
+	})
+})
+
+var _ = Describe("FilteredTracer", func() {
+	It("drops SentPacket and ReceivedPacket when DropPacketEvents is set, but keeps other events", func() {
+		inner := &recordingTracer{}
+		filtered := qlog.NewFilteredTracer(inner, qlog.FilterOptions{DropPacketEvents: true})
+
+		filtered.SentPacket(sentPacketOf(100))
+		filtered.ReceivedPacket(time.Now(), &wire.ExtendedHeader{}, 100, nil)
+		filtered.UpdatedMetrics(time.Now(), nil, 0, 0, 0)
+
+		Expect(inner.sentPackets).To(BeEmpty())
+		Expect(inner.receivedPackets).To(BeEmpty())
+		Expect(inner.updatedMetrics).To(Equal(1))
+	})
+
+	It("forwards 1-in-N UpdatedMetrics events when MetricsSampleRate is set", func() {
+		inner := &recordingTracer{}
+		filtered := qlog.NewFilteredTracer(inner, qlog.FilterOptions{MetricsSampleRate: 3})
+
+		for i := 0; i < 7; i++ {
+			filtered.UpdatedMetrics(time.Now(), nil, 0, 0, 0)
+		}
+		// calls 1, 4, 7 are forwarded (every 3rd, starting with the first)
+		Expect(inner.updatedMetrics).To(Equal(3))
+	})
+
+	It("rotates to a fresh tracer once RotateBytes is crossed, exporting the old one", func() {
+		first := &recordingTracer{}
+		var built []*recordingTracer
+		filtered := qlog.NewFilteredTracer(first, qlog.FilterOptions{
+			RotateBytes: 100,
+			NewWriter:   func() (io.WriteCloser, error) { return nopWriteCloser{}, nil },
+			NewTracer: func(io.WriteCloser, protocol.Perspective, protocol.ConnectionID) qlog.Tracer {
+				next := &recordingTracer{}
+				built = append(built, next)
+				return next
+			},
+		})
+
+		filtered.SentPacket(sentPacketOf(60))
+		Expect(first.exported).To(Equal(0))
+		Expect(built).To(BeEmpty())
+
+		filtered.SentPacket(sentPacketOf(60)) // crosses the 100 byte threshold
+		Expect(first.exported).To(Equal(1))
+		Expect(built).To(HaveLen(1))
+
+		filtered.SentPacket(sentPacketOf(1))
+		Expect(built[0].sentPackets).To(Equal([]protocol.ByteCount{1}))
+		Expect(first.sentPackets).To(Equal([]protocol.ByteCount{60, 60}))
+	})
+
+	It("keeps using the current tracer if NewWriter fails on rotation", func() {
+		first := &recordingTracer{}
+		filtered := qlog.NewFilteredTracer(first, qlog.FilterOptions{
+			RotateBytes: 100,
+			NewWriter:   func() (io.WriteCloser, error) { return nil, errors.New("disk full") },
+			NewTracer: func(io.WriteCloser, protocol.Perspective, protocol.ConnectionID) qlog.Tracer {
+				return &recordingTracer{}
+			},
+		})
+
+		filtered.SentPacket(sentPacketOf(150)) // crosses the threshold, but NewWriter fails
+		Expect(first.exported).To(Equal(0), "a failed rotation must not Export (and thereby close) the current inner tracer")
+
+		filtered.SentPacket(sentPacketOf(1))
+		Expect(first.sentPackets).To(Equal([]protocol.ByteCount{150, 1}), "further events must keep reaching the still-open inner tracer")
+	})
+})
+
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) { return len(p), nil }
+func (nopWriteCloser) Close() error                { return nil }
@@ -20,21 +20,50 @@ const eventChanSize = 50
 type Tracer interface {
 	Export() error
 	StartedConnection(t time.Time, local, remote net.Addr, version protocol.VersionNumber, srcConnID, destConnID protocol.ConnectionID)
+	// SentPacket and ReceivedPacket serialize each frame via qlog/frame.go's
+	// transformFrame, which isn't part of this tree. That switch needs a
+	// `case *wire.DatagramFrame` (calling writeDatagramFrameFields below) so a
+	// DATAGRAM frame inside a packet's frames array renders the same way as
+	// the standalone events just below; until that case lands, this request
+	// is only qlog-surface complete, not feature complete.
 	SentPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, ack *wire.AckFrame, frames []wire.Frame)
 	ReceivedRetry(time.Time, *wire.Header)
 	ReceivedPacket(t time.Time, hdr *wire.ExtendedHeader, packetSize protocol.ByteCount, frames []wire.Frame)
 	BufferedPacket(time.Time, PacketType)
+	// SentDatagramFrame, ReceivedDatagramFrame and DroppedDatagramFrame must be
+	// called from internal/wire and the datagram queue to ever fire outside of
+	// tests; that call site isn't part of this tree. Until it's wired in, this
+	// request delivers the qlog surface only, not the working feature.
+	SentDatagramFrame(t time.Time, length protocol.ByteCount)
+	ReceivedDatagramFrame(t time.Time, length protocol.ByteCount)
+	DroppedDatagramFrame(t time.Time, length protocol.ByteCount, reason DatagramDropReason)
 	UpdatedMetrics(t time.Time, rttStats *congestion.RTTStats, cwnd protocol.ByteCount, bytesInFLight protocol.ByteCount, packetsInFlight int)
-	LostPacket(time.Time, protocol.EncryptionLevel, protocol.PacketNumber, PacketLossReason)
+	LostPacket(t time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, reason PacketLossReason, bytesInFlight protocol.ByteCount, packetsInFlight int)
 	UpdatedPTOCount(time.Time, uint32)
 	UpdatedKeyFromTLS(time.Time, protocol.EncryptionLevel, protocol.Perspective)
 	UpdatedKey(t time.Time, generation protocol.KeyPhase, remote bool)
+	// ReceivedStatelessReset, ReceivedVersionNegotiationPacket and ClosedConnection
+	// must be called from session.go's close paths to ever fire outside of
+	// tests; that call site isn't part of this tree. Until it's wired in, this
+	// request delivers the qlog surface only, not the working feature.
+	ReceivedStatelessReset(t time.Time, token *protocol.StatelessResetToken)
+	ReceivedVersionNegotiationPacket(t time.Time, versions []protocol.VersionNumber)
+	ClosedConnection(t time.Time, reason CloseReason)
+	// UpdatedCongestionState must be called from internal/congestion (Cubic/
+	// Reno) on phase transitions, and LossTimerSet/LossTimerExpired from the
+	// loss/PTO timer in internal/ackhandler, to ever fire outside of tests;
+	// neither call site is part of this tree. Until they're wired in, this
+	// request delivers the qlog surface only, not the working feature.
+	UpdatedCongestionState(t time.Time, state CongestionState)
+	LossTimerSet(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel, deadline time.Time)
+	LossTimerExpired(t time.Time, timerType TimerType, encLevel protocol.EncryptionLevel)
 }
 
 type tracer struct {
 	w           io.WriteCloser
 	odcid       protocol.ConnectionID
 	perspective protocol.Perspective
+	streaming   bool
 
 	suffix     []byte
 	events     chan event
@@ -57,7 +86,28 @@ func NewTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.Connecti
 	return t
 }
 
+// NewStreamingTracer creates a new tracer that writes qlog in the JSON-SEQ format
+// (RFC 7464), one self-delimited record per line. Unlike the tracer returned by
+// NewTracer, every record is flushed as it is produced, so a file written by this
+// tracer is valid (and tail-able) even if the process crashes before Export is called.
+func NewStreamingTracer(w io.WriteCloser, p protocol.Perspective, odcid protocol.ConnectionID) Tracer {
+	t := &tracer{
+		w:           w,
+		perspective: p,
+		odcid:       odcid,
+		streaming:   true,
+		runStopped:  make(chan struct{}),
+		events:      make(chan event, eventChanSize),
+	}
+	go t.run()
+	return t
+}
+
 func (t *tracer) run() {
+	if t.streaming {
+		t.runStreaming()
+		return
+	}
 	defer close(t.runStopped)
 	buf := &bytes.Buffer{}
 	enc := gojay.NewEncoder(buf)
@@ -93,13 +143,79 @@ func (t *tracer) run() {
 	}
 }
 
-// Export writes a qlog.
+const (
+	jsonSeqRecordSeparator = 0x1e
+	jsonSeqLineTerminator  = '\n'
+)
+
+// jsonSeqHeader is the first record of a JSON-SEQ qlog (see NewStreamingTracer).
+// It carries the same vantage_point and common_fields that the single-array
+// format stores once in its top-level trace object.
+type jsonSeqHeader struct {
+	QlogVersion string
+	Title       string
+	Trace       trace
+}
+
+func (h jsonSeqHeader) IsNil() bool { return false }
+
+func (h jsonSeqHeader) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("qlog_version", h.QlogVersion)
+	enc.StringKey("title", h.Title)
+	enc.ObjectKey("trace", h.Trace)
+}
+
+// runStreaming writes the qlog header record, followed by one event record per line.
+// Every record is self-delimited (RS-prefixed, newline-terminated) and flushed
+// immediately, so the file is valid JSON-SEQ at every point in time, not just at the end.
+func (t *tracer) runStreaming() {
+	defer close(t.runStopped)
+	header := &jsonSeqHeader{
+		QlogVersion: "0.3",
+		Title:       "quic-go qlog",
+		Trace: trace{
+			VantagePoint: vantagePoint{Type: t.perspective},
+			CommonFields: commonFields{ODCID: connectionID(t.odcid), GroupID: connectionID(t.odcid)},
+			EventFields:  eventFields[:],
+		},
+	}
+	if err := t.writeStreamingRecord(header); err != nil {
+		t.encodeErr = err
+	}
+	for ev := range t.events {
+		if t.encodeErr != nil { // if encoding failed, just continue draining the event channel
+			continue
+		}
+		if err := t.writeStreamingRecord(ev); err != nil {
+			t.encodeErr = err
+		}
+	}
+}
+
+func (t *tracer) writeStreamingRecord(v gojay.MarshalerJSONObject) error {
+	buf := &bytes.Buffer{}
+	buf.WriteByte(jsonSeqRecordSeparator)
+	enc := gojay.NewEncoder(buf)
+	if err := enc.Encode(v); err != nil {
+		return err
+	}
+	buf.WriteByte(jsonSeqLineTerminator)
+	_, err := t.w.Write(buf.Bytes())
+	return err
+}
+
+// Export writes a qlog. For a streaming tracer, all records have already been
+// flushed as they occurred, so this only waits for the run loop to drain and
+// closes the writer.
 func (t *tracer) Export() error {
 	close(t.events)
 	<-t.runStopped
 	if t.encodeErr != nil {
 		return t.encodeErr
 	}
+	if t.streaming {
+		return t.w.Close()
+	}
 	if _, err := t.w.Write(t.suffix); err != nil {
 		return err
 	}
@@ -185,6 +301,30 @@ func (t *tracer) BufferedPacket(time time.Time, packetType PacketType) {
 	}
 }
 
+func (t *tracer) SentDatagramFrame(time time.Time, length protocol.ByteCount) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventDatagramFrameSent{Length: length},
+	}
+}
+
+func (t *tracer) ReceivedDatagramFrame(time time.Time, length protocol.ByteCount) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventDatagramFrameReceived{Length: length},
+	}
+}
+
+func (t *tracer) DroppedDatagramFrame(time time.Time, length protocol.ByteCount, reason DatagramDropReason) {
+	t.events <- event{
+		Time: time,
+		eventDetails: eventDatagramFrameDropped{
+			Length:  length,
+			Trigger: reason,
+		},
+	}
+}
+
 func (t *tracer) UpdatedMetrics(time time.Time, rttStats *congestion.RTTStats, cwnd, bytesInFlight protocol.ByteCount, packetsInFlight int) {
 	t.events <- event{
 		Time: time,
@@ -200,13 +340,15 @@ func (t *tracer) UpdatedMetrics(time time.Time, rttStats *congestion.RTTStats, c
 	}
 }
 
-func (t *tracer) LostPacket(time time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason PacketLossReason) {
+func (t *tracer) LostPacket(time time.Time, encLevel protocol.EncryptionLevel, pn protocol.PacketNumber, lossReason PacketLossReason, bytesInFlight protocol.ByteCount, packetsInFlight int) {
 	t.events <- event{
 		Time: time,
 		eventDetails: eventPacketLost{
-			PacketType:   getPacketTypeFromEncryptionLevel(encLevel),
-			PacketNumber: pn,
-			Trigger:      lossReason,
+			PacketType:      getPacketTypeFromEncryptionLevel(encLevel),
+			PacketNumber:    pn,
+			Trigger:         lossReason,
+			BytesInFlight:   bytesInFlight,
+			PacketsInFlight: packetsInFlight,
 		},
 	}
 }
@@ -250,3 +392,381 @@ func (t *tracer) UpdatedKey(time time.Time, generation protocol.KeyPhase, remote
 		},
 	}
 }
+
+func (t *tracer) ReceivedStatelessReset(time time.Time, token *protocol.StatelessResetToken) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventStatelessResetReceived{Token: token},
+	}
+}
+
+func (t *tracer) ReceivedVersionNegotiationPacket(time time.Time, versions []protocol.VersionNumber) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventVersionNegotiationReceived{Versions: versions},
+	}
+}
+
+func (t *tracer) ClosedConnection(time time.Time, reason CloseReason) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventConnectionClosed{Reason: reason},
+	}
+}
+
+func (t *tracer) UpdatedCongestionState(time time.Time, state CongestionState) {
+	t.events <- event{
+		Time:         time,
+		eventDetails: eventCongestionStateUpdated{State: state},
+	}
+}
+
+func (t *tracer) LossTimerSet(time time.Time, timerType TimerType, encLevel protocol.EncryptionLevel, deadline time.Time) {
+	t.events <- event{
+		Time: time,
+		eventDetails: eventLossTimerSet{
+			TimerType: timerType,
+			EncLevel:  encLevel,
+			Delta:     deadline.Sub(time),
+		},
+	}
+}
+
+func (t *tracer) LossTimerExpired(time time.Time, timerType TimerType, encLevel protocol.EncryptionLevel) {
+	t.events <- event{
+		Time: time,
+		eventDetails: eventLossTimerExpired{
+			TimerType: timerType,
+			EncLevel:  encLevel,
+		},
+	}
+}
+
+// DatagramDropReason is the trigger for a dropped QUIC DATAGRAM frame (RFC 9221).
+type DatagramDropReason uint8
+
+const (
+	// DatagramDropReasonQueueOverflow is used when the send or receive queue is full.
+	DatagramDropReasonQueueOverflow DatagramDropReason = iota
+	// DatagramDropReasonTooLarge is used when the datagram doesn't fit into a single packet.
+	DatagramDropReasonTooLarge
+	// DatagramDropReasonDisabledByPeer is used when the peer didn't negotiate support for datagrams.
+	DatagramDropReasonDisabledByPeer
+)
+
+func (r DatagramDropReason) String() string {
+	switch r {
+	case DatagramDropReasonQueueOverflow:
+		return "queue_overflow"
+	case DatagramDropReasonTooLarge:
+		return "too_large"
+	case DatagramDropReasonDisabledByPeer:
+		return "disabled_by_peer"
+	default:
+		return "unknown"
+	}
+}
+
+// category is the top-level qlog category an event is grouped under
+// (https://www.ietf.org/archive/id/draft-ietf-quic-qlog-main-schema-04.html#section-5).
+type category uint8
+
+const (
+	categoryConnectivity category = iota
+	categoryTransport
+	categoryRecovery
+	categorySecurity
+)
+
+func (c category) String() string {
+	switch c {
+	case categoryConnectivity:
+		return "connectivity"
+	case categoryTransport:
+		return "transport"
+	case categoryRecovery:
+		return "recovery"
+	case categorySecurity:
+		return "security"
+	default:
+		return "unknown"
+	}
+}
+
+// writeDatagramFrameFields writes the frame_type and length fields shared by
+// every qlog representation of a DATAGRAM frame (RFC 9221): the
+// datagram_frame_sent/received/dropped events below, and qlog/frame.go's transformFrame,
+// whose *wire.DatagramFrame case should call this too rather than encoding
+// the same two fields a second way.
+func writeDatagramFrameFields(enc *gojay.Encoder, length protocol.ByteCount) {
+	enc.StringKey("frame_type", "datagram")
+	enc.Int64Key("length", int64(length))
+}
+
+type eventDatagramFrameSent struct {
+	Length protocol.ByteCount
+}
+
+func (e eventDatagramFrameSent) Category() category { return categoryTransport }
+func (e eventDatagramFrameSent) Name() string       { return "datagram_frame_sent" }
+func (e eventDatagramFrameSent) IsNil() bool        { return false }
+
+func (e eventDatagramFrameSent) MarshalJSONObject(enc *gojay.Encoder) {
+	writeDatagramFrameFields(enc, e.Length)
+}
+
+type eventDatagramFrameReceived struct {
+	Length protocol.ByteCount
+}
+
+func (e eventDatagramFrameReceived) Category() category { return categoryTransport }
+func (e eventDatagramFrameReceived) Name() string       { return "datagram_frame_received" }
+func (e eventDatagramFrameReceived) IsNil() bool        { return false }
+
+func (e eventDatagramFrameReceived) MarshalJSONObject(enc *gojay.Encoder) {
+	writeDatagramFrameFields(enc, e.Length)
+}
+
+type eventDatagramFrameDropped struct {
+	Length  protocol.ByteCount
+	Trigger DatagramDropReason
+}
+
+func (e eventDatagramFrameDropped) Category() category { return categoryTransport }
+func (e eventDatagramFrameDropped) Name() string       { return "datagram_frame_dropped" }
+func (e eventDatagramFrameDropped) IsNil() bool        { return false }
+
+func (e eventDatagramFrameDropped) MarshalJSONObject(enc *gojay.Encoder) {
+	writeDatagramFrameFields(enc, e.Length)
+	enc.StringKey("trigger", e.Trigger.String())
+}
+
+// CloseReasonKind identifies why a connection was closed.
+type CloseReasonKind uint8
+
+const (
+	// CloseReasonIdleTimeout is used when the connection timed out due to inactivity.
+	CloseReasonIdleTimeout CloseReasonKind = iota
+	// CloseReasonHandshakeTimeout is used when the handshake didn't complete in time.
+	CloseReasonHandshakeTimeout
+	// CloseReasonApplicationClose is used when the application closed the connection.
+	CloseReasonApplicationClose
+	// CloseReasonTransportClose is used when a CONNECTION_CLOSE frame was sent or received.
+	CloseReasonTransportClose
+	// CloseReasonStatelessReset is used when the connection was closed by a stateless reset.
+	CloseReasonStatelessReset
+	// CloseReasonVersionMismatch is used when version negotiation failed.
+	CloseReasonVersionMismatch
+)
+
+func (k CloseReasonKind) String() string {
+	switch k {
+	case CloseReasonIdleTimeout:
+		return "idle_timeout"
+	case CloseReasonHandshakeTimeout:
+		return "handshake_timeout"
+	case CloseReasonApplicationClose:
+		return "application_close"
+	case CloseReasonTransportClose:
+		return "transport_close"
+	case CloseReasonStatelessReset:
+		return "stateless_reset"
+	case CloseReasonVersionMismatch:
+		return "version_mismatch"
+	default:
+		return "unknown"
+	}
+}
+
+// CloseReason describes why a connection was closed. It's a tagged union:
+// only the fields relevant to Kind are populated. Use one of the
+// NewXCloseReason constructors below instead of constructing this directly.
+type CloseReason struct {
+	Kind CloseReasonKind
+
+	// set for CloseReasonApplicationClose and CloseReasonTransportClose
+	ErrorCode uint64
+	Reason    string
+	// set for CloseReasonTransportClose only
+	FrameType uint64
+}
+
+// NewIdleTimeoutCloseReason says the connection was closed because it was idle.
+func NewIdleTimeoutCloseReason() CloseReason {
+	return CloseReason{Kind: CloseReasonIdleTimeout}
+}
+
+// NewHandshakeTimeoutCloseReason says the connection was closed because the handshake timed out.
+func NewHandshakeTimeoutCloseReason() CloseReason {
+	return CloseReason{Kind: CloseReasonHandshakeTimeout}
+}
+
+// NewApplicationCloseReason says the connection was closed by the application.
+func NewApplicationCloseReason(errorCode uint64, reason string) CloseReason {
+	return CloseReason{Kind: CloseReasonApplicationClose, ErrorCode: errorCode, Reason: reason}
+}
+
+// NewTransportCloseReason says the connection was closed via a CONNECTION_CLOSE frame.
+func NewTransportCloseReason(errorCode uint64, frameType uint64, reason string) CloseReason {
+	return CloseReason{Kind: CloseReasonTransportClose, ErrorCode: errorCode, FrameType: frameType, Reason: reason}
+}
+
+// NewStatelessResetCloseReason says the connection was closed by a stateless reset.
+func NewStatelessResetCloseReason() CloseReason {
+	return CloseReason{Kind: CloseReasonStatelessReset}
+}
+
+// NewVersionMismatchCloseReason says the connection was closed because version negotiation failed.
+func NewVersionMismatchCloseReason() CloseReason {
+	return CloseReason{Kind: CloseReasonVersionMismatch}
+}
+
+type eventConnectionClosed struct {
+	Reason CloseReason
+}
+
+func (e eventConnectionClosed) Category() category { return categoryConnectivity }
+func (e eventConnectionClosed) Name() string       { return "connection_closed" }
+func (e eventConnectionClosed) IsNil() bool        { return false }
+
+func (e eventConnectionClosed) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("trigger", e.Reason.Kind.String())
+	if e.Reason.Kind == CloseReasonApplicationClose || e.Reason.Kind == CloseReasonTransportClose {
+		enc.Uint64Key("error_code", e.Reason.ErrorCode)
+		enc.StringKey("reason", e.Reason.Reason)
+	}
+	if e.Reason.Kind == CloseReasonTransportClose {
+		enc.Uint64Key("frame_type", e.Reason.FrameType)
+	}
+}
+
+type eventStatelessResetReceived struct {
+	Token *protocol.StatelessResetToken
+}
+
+func (e eventStatelessResetReceived) Category() category { return categoryConnectivity }
+func (e eventStatelessResetReceived) Name() string       { return "stateless_reset_received" }
+func (e eventStatelessResetReceived) IsNil() bool        { return false }
+
+func (e eventStatelessResetReceived) MarshalJSONObject(enc *gojay.Encoder) {
+	if e.Token != nil {
+		enc.StringKey("stateless_reset_token", fmt.Sprintf("%x", *e.Token))
+	}
+}
+
+type eventVersionNegotiationReceived struct {
+	Versions []protocol.VersionNumber
+}
+
+func (e eventVersionNegotiationReceived) Category() category { return categoryConnectivity }
+func (e eventVersionNegotiationReceived) Name() string       { return "version_information" }
+func (e eventVersionNegotiationReceived) IsNil() bool        { return false }
+
+func (e eventVersionNegotiationReceived) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.ArrayKey("versions", versionSlice(e.Versions))
+}
+
+type versionSlice []protocol.VersionNumber
+
+func (v versionSlice) MarshalJSONArray(enc *gojay.Encoder) {
+	for _, ver := range v {
+		enc.StringElement(ver.String())
+	}
+}
+
+func (v versionSlice) IsNil() bool { return v == nil }
+
+// CongestionState is the state of the congestion controller, reported via
+// UpdatedCongestionState whenever the controller transitions between phases.
+type CongestionState uint8
+
+const (
+	// CongestionStateSlowStart is used during slow start.
+	CongestionStateSlowStart CongestionState = iota
+	// CongestionStateCongestionAvoidance is used during congestion avoidance.
+	CongestionStateCongestionAvoidance
+	// CongestionStateApplicationLimited is used when the connection is application-limited.
+	CongestionStateApplicationLimited
+	// CongestionStateRecovery is used while recovering from a loss.
+	CongestionStateRecovery
+)
+
+func (s CongestionState) String() string {
+	switch s {
+	case CongestionStateSlowStart:
+		return "slow_start"
+	case CongestionStateCongestionAvoidance:
+		return "congestion_avoidance"
+	case CongestionStateApplicationLimited:
+		return "application_limited"
+	case CongestionStateRecovery:
+		return "recovery"
+	default:
+		return "unknown"
+	}
+}
+
+// TimerType identifies the loss-detection timer reported by LossTimerSet and LossTimerExpired.
+type TimerType uint8
+
+const (
+	// TimerTypeACK is the loss detection timer armed for a previously sent, not yet acknowledged packet.
+	TimerTypeACK TimerType = iota
+	// TimerTypePTO is the probe timeout timer.
+	TimerTypePTO
+)
+
+func (t TimerType) String() string {
+	switch t {
+	case TimerTypeACK:
+		return "ack"
+	case TimerTypePTO:
+		return "pto"
+	default:
+		return "unknown"
+	}
+}
+
+type eventCongestionStateUpdated struct {
+	State CongestionState
+}
+
+func (e eventCongestionStateUpdated) Category() category { return categoryRecovery }
+func (e eventCongestionStateUpdated) Name() string       { return "congestion_state_updated" }
+func (e eventCongestionStateUpdated) IsNil() bool        { return false }
+
+func (e eventCongestionStateUpdated) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("new", e.State.String())
+}
+
+type eventLossTimerSet struct {
+	TimerType TimerType
+	EncLevel  protocol.EncryptionLevel
+	Delta     time.Duration
+}
+
+func (e eventLossTimerSet) Category() category { return categoryRecovery }
+func (e eventLossTimerSet) Name() string       { return "loss_timer_updated" }
+func (e eventLossTimerSet) IsNil() bool        { return false }
+
+func (e eventLossTimerSet) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("event_type", "set")
+	enc.StringKey("timer_type", e.TimerType.String())
+	enc.StringKey("packet_number_space", encLevelToPacketNumberSpace(e.EncLevel))
+	enc.Float64Key("delta", milliseconds(e.Delta))
+}
+
+type eventLossTimerExpired struct {
+	TimerType TimerType
+	EncLevel  protocol.EncryptionLevel
+}
+
+func (e eventLossTimerExpired) Category() category { return categoryRecovery }
+func (e eventLossTimerExpired) Name() string       { return "loss_timer_updated" }
+func (e eventLossTimerExpired) IsNil() bool        { return false }
+
+func (e eventLossTimerExpired) MarshalJSONObject(enc *gojay.Encoder) {
+	enc.StringKey("event_type", "expired")
+	enc.StringKey("timer_type", e.TimerType.String())
+	enc.StringKey("packet_number_space", encLevelToPacketNumberSpace(e.EncLevel))
+}
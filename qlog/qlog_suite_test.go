@@ -0,0 +1,13 @@
+package qlog_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+)
+
+func TestQlog(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "qlog suite")
+}
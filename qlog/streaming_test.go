@@ -0,0 +1,102 @@
+package qlog_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"time"
+
+	. "github.com/onsi/ginkgo"
+	. "github.com/onsi/gomega"
+
+	"github.com/lucas-clemente/quic-go/internal/protocol"
+	"github.com/lucas-clemente/quic-go/qlog"
+)
+
+// closableBuffer is an io.WriteCloser backed by a bytes.Buffer, so tests can
+// inspect what a streaming tracer wrote without touching the filesystem.
+type closableBuffer struct {
+	bytes.Buffer
+	closed bool
+}
+
+func (b *closableBuffer) Close() error {
+	b.closed = true
+	return nil
+}
+
+// splitJSONSeqRecords splits a JSON-SEQ byte stream (RFC 7464: each record
+// prefixed by 0x1E and terminated by '\n') into its individual JSON payloads.
+// A dangling, newline-less record at the end of data (as a crash mid-write
+// would produce) is reported via truncated rather than returned as a record.
+func splitJSONSeqRecords(data []byte) (records [][]byte, truncated bool) {
+	for _, chunk := range bytes.Split(data, []byte{0x1e}) {
+		if len(chunk) == 0 {
+			continue
+		}
+		if chunk[len(chunk)-1] != '\n' {
+			truncated = true
+			continue
+		}
+		records = append(records, chunk[:len(chunk)-1])
+	}
+	return records, truncated
+}
+
+var _ = Describe("Streaming tracer", func() {
+	var buf *closableBuffer
+
+	BeforeEach(func() {
+		buf = &closableBuffer{}
+	})
+
+	It("writes a qlog header record followed by one record per event", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.StartedConnection(
+			time.Now(),
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4321},
+			protocol.VersionNumber(1),
+			protocol.ConnectionID{1, 2, 3, 4},
+			protocol.ConnectionID{5, 6, 7, 8},
+		)
+		Expect(tracer.Export()).To(Succeed())
+		Expect(buf.closed).To(BeTrue())
+
+		records, truncated := splitJSONSeqRecords(buf.Bytes())
+		Expect(truncated).To(BeFalse())
+		Expect(records).To(HaveLen(2))
+
+		var header map[string]interface{}
+		Expect(json.Unmarshal(records[0], &header)).To(Succeed())
+		Expect(header).To(HaveKey("qlog_version"))
+		Expect(header).To(HaveKey("trace"))
+
+		var ev map[string]interface{}
+		Expect(json.Unmarshal(records[1], &ev)).To(Succeed())
+	})
+
+	It("leaves every record written so far valid if the file is truncated mid-write", func() {
+		tracer := qlog.NewStreamingTracer(buf, protocol.PerspectiveClient, protocol.ConnectionID{1, 2, 3, 4})
+		tracer.StartedConnection(
+			time.Now(),
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 1234},
+			&net.UDPAddr{IP: net.IPv4(127, 0, 0, 1), Port: 4321},
+			protocol.VersionNumber(1),
+			protocol.ConnectionID{1, 2, 3, 4},
+			protocol.ConnectionID{5, 6, 7, 8},
+		)
+		Expect(tracer.Export()).To(Succeed())
+
+		full := buf.Bytes()
+		simulatedCrash := full[:len(full)-5] // cut off the tail of the last record, as a crash would
+
+		records, truncated := splitJSONSeqRecords(simulatedCrash)
+		Expect(truncated).To(BeTrue())
+		Expect(records).ToNot(BeEmpty())
+		for _, r := range records {
+			var v map[string]interface{}
+			Expect(json.Unmarshal(r, &v)).To(Succeed())
+		}
+	})
+})